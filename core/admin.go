@@ -0,0 +1,172 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PluginState describes where a plugin is in its dynamic enable/disable
+// lifecycle. It is tracked independently of the one-shot Init/AfterInit/
+// Close lifecycle driven by Agent.Start/Stop.
+type PluginState int
+
+const (
+	// PluginStateReady is the normal, fully initialized and enabled state.
+	PluginStateReady PluginState = iota
+	// PluginStateDisabling is set while a Disable() call is in flight.
+	PluginStateDisabling
+	// PluginStateDisabled is set once Disable() has returned successfully.
+	PluginStateDisabled
+	// PluginStateEnabling is set while an Enable() call is in flight.
+	PluginStateEnabling
+)
+
+// String gives the PluginState a human-readable form, used in admin API
+// responses.
+func (s PluginState) String() string {
+	switch s {
+	case PluginStateReady:
+		return "Ready"
+	case PluginStateDisabling:
+		return "Disabling"
+	case PluginStateDisabled:
+		return "Disabled"
+	case PluginStateEnabling:
+		return "Enabling"
+	default:
+		return "Unknown"
+	}
+}
+
+// Toggleable is optionally implemented by a plugin that can be quiesced
+// and resumed while the agent keeps running, e.g. so an operator can drop
+// a misbehaving Kafka producer without a full agent restart. Plugins that
+// don't implement it reject dynamic enable/disable requests.
+type Toggleable interface {
+	// Disable releases/quiesces whatever resources the plugin holds. It
+	// must be safe to call Enable afterwards to resume the plugin.
+	Disable() error
+	// Enable resumes a plugin previously put in PluginStateDisabled.
+	Enable() error
+}
+
+// pluginAdmin tracks the runtime state of every plugin and serializes
+// enable/disable transitions on a per-plugin basis.
+type pluginAdmin struct {
+	mu     sync.Mutex
+	states map[PluginName]PluginState
+	locks  map[PluginName]*sync.Mutex
+}
+
+func newPluginAdmin(plugins []*NamedPlugin) *pluginAdmin {
+	admin := &pluginAdmin{
+		states: make(map[PluginName]PluginState, len(plugins)),
+		locks:  make(map[PluginName]*sync.Mutex, len(plugins)),
+	}
+	for _, plug := range plugins {
+		admin.states[plug.PluginName] = PluginStateReady
+		admin.locks[plug.PluginName] = &sync.Mutex{}
+	}
+	return admin
+}
+
+// PluginStates returns a snapshot of the current runtime state of every
+// plugin, keyed by plugin name.
+func (agent *Agent) PluginStates() map[PluginName]PluginState {
+	agent.admin.mu.Lock()
+	defer agent.admin.mu.Unlock()
+
+	snapshot := make(map[PluginName]PluginState, len(agent.admin.states))
+	for name, state := range agent.admin.states {
+		snapshot[name] = state
+	}
+	return snapshot
+}
+
+// DisablePlugin quiesces the named plugin. The plugin must implement
+// Toggleable and currently be PluginStateReady, otherwise the request is
+// rejected. Concurrent enable/disable requests for the same plugin are
+// serialized; requests for different plugins proceed independently.
+func (agent *Agent) DisablePlugin(name PluginName) error {
+	return agent.togglePlugin(name, PluginStateReady, PluginStateDisabling, PluginStateDisabled, PluginStateReady, Toggleable.Disable)
+}
+
+// EnablePlugin resumes the named plugin previously quiesced with
+// DisablePlugin. The plugin must currently be PluginStateDisabled,
+// otherwise the request is rejected.
+func (agent *Agent) EnablePlugin(name PluginName) error {
+	return agent.togglePlugin(name, PluginStateDisabled, PluginStateEnabling, PluginStateReady, PluginStateDisabled, Toggleable.Enable)
+}
+
+// togglePlugin checks that name is currently in precondition, then drives
+// it through transitional while action runs, settling on onSuccess, or
+// back on onFailure (the state name was in before this call) if action
+// errors out. Rejecting anything but precondition prevents a second
+// Disable()/Enable() from firing on a plugin that's already mid-
+// transition or already in the target state: Toggleable's contract only
+// guarantees Disable is safe to call once before a matching Enable, and
+// vice versa.
+func (agent *Agent) togglePlugin(name PluginName, precondition, transitional, onSuccess, onFailure PluginState, action func(Toggleable) error) error {
+	var plug *NamedPlugin
+	for _, p := range agent.plugins {
+		if p.PluginName == name {
+			plug = p
+			break
+		}
+	}
+	if plug == nil {
+		return fmt.Errorf("plugin %s not found", name)
+	}
+
+	toggleable, ok := plug.Plugin.(Toggleable)
+	if !ok {
+		return fmt.Errorf("plugin %s does not support dynamic enable/disable", name)
+	}
+
+	lock := agent.admin.locks[name]
+	lock.Lock()
+	defer lock.Unlock()
+
+	agent.admin.mu.Lock()
+	if current := agent.admin.states[name]; current != precondition {
+		agent.admin.mu.Unlock()
+		return fmt.Errorf("plugin %s: cannot transition from %s (expected %s)", name, current, precondition)
+	}
+	agent.admin.states[name] = transitional
+	agent.admin.mu.Unlock()
+
+	err := action(toggleable)
+
+	agent.admin.mu.Lock()
+	if err != nil {
+		agent.admin.states[name] = onFailure
+	} else {
+		agent.admin.states[name] = onSuccess
+	}
+	agent.admin.mu.Unlock()
+
+	return err
+}
+
+// NamedPlugins returns the registered plugins keyed by name.
+func (agent *Agent) NamedPlugins() map[PluginName]Plugin {
+	named := make(map[PluginName]Plugin, len(agent.plugins))
+	for _, plug := range agent.plugins {
+		named[plug.PluginName] = plug.Plugin
+	}
+	return named
+}
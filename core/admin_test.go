@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+// toggleablePlugin is a no-op Plugin that also implements Toggleable,
+// returning whatever Disable/Enable are told to.
+type toggleablePlugin struct {
+	disableErr error
+	enableErr  error
+}
+
+func (p *toggleablePlugin) Init() error  { return nil }
+func (p *toggleablePlugin) Close() error { return nil }
+
+func (p *toggleablePlugin) Disable() error { return p.disableErr }
+func (p *toggleablePlugin) Enable() error  { return p.enableErr }
+
+func newToggleAgent(plug *toggleablePlugin) *Agent {
+	plugins := []*NamedPlugin{{PluginName("toggleable"), plug}}
+	return &Agent{plugins: plugins, admin: newPluginAdmin(plugins)}
+}
+
+func TestDisablePluginSuccess(t *testing.T) {
+	agent := newToggleAgent(&toggleablePlugin{})
+
+	if err := agent.DisablePlugin("toggleable"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := agent.PluginStates()["toggleable"]; got != PluginStateDisabled {
+		t.Fatalf("got state %s, want %s", got, PluginStateDisabled)
+	}
+}
+
+func TestDisablePluginFailureRevertsToReady(t *testing.T) {
+	agent := newToggleAgent(&toggleablePlugin{disableErr: errors.New("boom")})
+
+	if err := agent.DisablePlugin("toggleable"); err == nil {
+		t.Fatalf("expected an error from Disable()")
+	}
+	if got := agent.PluginStates()["toggleable"]; got != PluginStateReady {
+		t.Fatalf("got state %s, want %s", got, PluginStateReady)
+	}
+}
+
+func TestEnablePluginFailureRevertsToDisabled(t *testing.T) {
+	agent := newToggleAgent(&toggleablePlugin{enableErr: errors.New("boom")})
+
+	agent.admin.mu.Lock()
+	agent.admin.states["toggleable"] = PluginStateDisabled
+	agent.admin.mu.Unlock()
+
+	if err := agent.EnablePlugin("toggleable"); err == nil {
+		t.Fatalf("expected an error from Enable()")
+	}
+	if got := agent.PluginStates()["toggleable"]; got != PluginStateDisabled {
+		t.Fatalf("got state %s, want %s", got, PluginStateDisabled)
+	}
+}
+
+func TestDisablePluginRejectsWrongState(t *testing.T) {
+	agent := newToggleAgent(&toggleablePlugin{})
+
+	agent.admin.mu.Lock()
+	agent.admin.states["toggleable"] = PluginStateDisabled
+	agent.admin.mu.Unlock()
+
+	if err := agent.DisablePlugin("toggleable"); err == nil {
+		t.Fatalf("expected DisablePlugin to reject a plugin that's already Disabled")
+	}
+	if got := agent.PluginStates()["toggleable"]; got != PluginStateDisabled {
+		t.Fatalf("rejected transition must leave state untouched, got %s", got)
+	}
+}
+
+func TestEnablePluginRejectsWrongState(t *testing.T) {
+	agent := newToggleAgent(&toggleablePlugin{})
+
+	if err := agent.EnablePlugin("toggleable"); err == nil {
+		t.Fatalf("expected EnablePlugin to reject a plugin that's still Ready")
+	}
+	if got := agent.PluginStates()["toggleable"]; got != PluginStateReady {
+		t.Fatalf("rejected transition must leave state untouched, got %s", got)
+	}
+}
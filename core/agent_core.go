@@ -17,6 +17,7 @@ package core
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/ligato/cn-infra/logging"
@@ -37,38 +38,110 @@ type Agent struct {
 	logging.Logger
 	// agent startup details
 	startup
+	// tracks the runtime (as opposed to init-time) state of every plugin,
+	// for the dynamic enable/disable admin API
+	admin *pluginAdmin
 }
 
 type startup struct {
 	// The startup/initialization must take no longer that maxStartup.
 	MaxStartupTime time.Duration
-	// successfully initialized plugins
-	initSuccess []*NamedPlugin
 	// init duration in ns
 	initDuration int64
-	// successfully after-initialized plugins
-	afterInitSuccess []*NamedPlugin
 	// after-init duration in ns
 	afterInitDuration int64
 	// the field is set before initialization of every plugin with its name
 	currentlyProcessing string
+	// guards events, which is appended to from multiple init goroutines
+	// running in parallel
+	mu sync.Mutex
+	// registered LifecycleListeners, notified of every LifecycleEvent
+	listeners []LifecycleListener
+	// full lifecycle history, in publish order; querying this replaces
+	// the old calculateDiff-against-a-success-slice dance
+	events []LifecycleEvent
 }
 
-const (
-	logErrorFmt       = "plugin %s: init error '%s', duration %d"
-	logSuccessFmt     = "plugin %s: init success"
-	logPostErrorFmt   = "plugin %s: post-init error '%s', duration %d"
-	logPostSuccessFmt = "plugin %s: post-init success"
-	logTimeoutFmt     = "plugin %s not completed before timeout"
-)
+// DependencyAware is optionally implemented by a plugin that wants to opt
+// into finer-grained parallelism than the default. It is detected the same
+// way PostInit is: via a type assertion on the plugin instance, not via an
+// interface the plugin's struct needs to declare.
+//
+// A plugin that does NOT implement DependencyAware keeps the agent's
+// original, fully-sequential semantics: it implicitly depends on every
+// plugin that precedes it in the slice passed to NewAgent, so it never
+// races a plugin declared before it (e.g. one that reaches into another
+// plugin's exported fields from its own Init).
+type DependencyAware interface {
+	// DependsOn returns the names of the plugins that must successfully
+	// Init (and, for the AfterInit pass, AfterInit) before this plugin.
+	DependsOn() []PluginName
+}
+
+// pluginLevels groups plugins into waves that can safely Init (or
+// AfterInit) in parallel: every plugin in a given wave only depends on
+// plugins in earlier waves. Plugins that don't implement DependencyAware
+// are given an implicit dependency on every plugin declared before them,
+// preserving the pre-DAG sequential order by default.
+func (agent *Agent) pluginLevels() ([][]*NamedPlugin, error) {
+	dependsOn := make(map[PluginName][]PluginName, len(agent.plugins))
+	remaining := make(map[PluginName]*NamedPlugin, len(agent.plugins))
+	for i, plug := range agent.plugins {
+		remaining[plug.PluginName] = plug
+		if aware, ok := plug.Plugin.(DependencyAware); ok {
+			dependsOn[plug.PluginName] = aware.DependsOn()
+			continue
+		}
+		deps := make([]PluginName, i)
+		for j := 0; j < i; j++ {
+			deps[j] = agent.plugins[j].PluginName
+		}
+		dependsOn[plug.PluginName] = deps
+	}
+
+	var levels [][]*NamedPlugin
+	for len(remaining) > 0 {
+		var wave []*NamedPlugin
+		for name, plug := range remaining {
+			ready := true
+			for _, dep := range dependsOn[name] {
+				if _, stillPending := remaining[dep]; stillPending {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				wave = append(wave, plug)
+			}
+		}
+		if len(wave) == 0 {
+			var stuck []PluginName
+			for name := range remaining {
+				stuck = append(stuck, name)
+			}
+			return nil, fmt.Errorf("plugin dependency cycle detected among: %v", stuck)
+		}
+		for _, plug := range wave {
+			delete(remaining, plug.PluginName)
+		}
+		levels = append(levels, wave)
+	}
+	return levels, nil
+}
 
-// NewAgent returns a new instance of the Agent with plugins.
+// NewAgent returns a new instance of the Agent with plugins. A
+// JSONLogListener backed by logger is registered by default, so every
+// lifecycle event is logged through logger as structured fields (failures
+// at error level, everything else at debug level); call RegisterLifecycleListener
+// to add more, e.g. a PrometheusListener or a ChannelListener for tests.
 func NewAgent(logger logging.Logger, maxStartup time.Duration, plugins ...*NamedPlugin) *Agent {
 	a := Agent{
 		plugins,
 		logger,
 		startup{MaxStartupTime: maxStartup},
+		newPluginAdmin(plugins),
 	}
+	a.listeners = append(a.listeners, NewJSONLogListener(logger))
 	return &a
 }
 
@@ -107,23 +180,19 @@ func (agent *Agent) Start() error {
 	//block until all Plugins are initialized or timeout expires
 	select {
 	case err := <-errChannel:
-		errInit := agent.calculateDiff(agent.initSuccess)
-		errAfterInit := agent.calculateDiff(agent.afterInitSuccess)
-		agent.WithFields(logging.Fields{"AfterInitFail: ": errAfterInit, "AfterInit succ: ": agent.afterInitSuccess,
-			"Init succ: ": agent.initSuccess, "Init fail: ": errInit}).Error("Agent failed to start")
-
-		// Error is logged in handleInit/AfterInit
+		// A failed Init/AfterInit/Close already published the
+		// LifecycleEvent that explains what failed; registered listeners
+		// (e.g. JSONLogListener) have logged it already. A dependency
+		// cycle found by pluginLevels publishes no event and is only
+		// returned here.
 		return err
 	case <-doneChannel:
 		agent.WithField("durationNs:", agent.initDuration+agent.afterInitDuration).Info("All plugins initialized successfully")
 		return nil
 	case <-time.After(agent.MaxStartupTime):
-		errInit := agent.calculateDiff(agent.initSuccess)
-		errAfterInit := agent.calculateDiff(agent.afterInitSuccess)
-		agent.WithFields(logging.Fields{"AfterInitFail: ": errAfterInit, "AfterInit succ: ": agent.afterInitSuccess,
-			"Init succ: ": agent.initSuccess, "Init fail: ": errInit}).Error("Agent failed to start")
-
-		return fmt.Errorf(logTimeoutFmt, agent.currentlyProcessing)
+		err := fmt.Errorf("plugin %s not completed before timeout", agent.currentlyProcessing)
+		agent.publish(LifecycleEvent{Type: AgentTimedOut, Err: err})
+		return err
 	}
 }
 
@@ -136,16 +205,23 @@ func (agent *Agent) Stop() error {
 	agent.Info("Stopping agent...")
 	errMsg := ""
 	for i := len(agent.plugins) - 1; i >= 0; i-- {
-		agent.WithField("pluginName", agent.plugins[i].PluginName).Debug("Stopping plugin begin")
-		err := safeclose.Close(agent.plugins[i].Plugin)
+		plug := agent.plugins[i]
+		agent.publish(LifecycleEvent{Type: PluginCloseStarted, Plugin: plug.PluginName})
+
+		startTime := time.Now()
+		err := safeclose.Close(plug.Plugin)
+		duration := time.Since(startTime)
+
 		if err != nil {
+			agent.publish(LifecycleEvent{Type: PluginCloseFailed, Plugin: plug.PluginName, Err: err, Duration: duration})
 			if len(errMsg) > 0 {
 				errMsg += "; "
 			}
-			errMsg += string(agent.plugins[i].PluginName)
+			errMsg += string(plug.PluginName)
 			errMsg += ": " + err.Error()
+		} else {
+			agent.publish(LifecycleEvent{Type: PluginCloseSucceeded, Plugin: plug.PluginName, Duration: duration})
 		}
-		agent.WithField("pluginName", agent.plugins[i].PluginName).Debug("Stopping plugin end ", err)
 	}
 
 	agent.Debug("Agent stopped")
@@ -156,27 +232,73 @@ func (agent *Agent) Stop() error {
 	return nil
 }
 
-// initPlugins calls Init() an all plugins on the list
+// initResult carries the outcome of a single plugin's Init()/AfterInit()
+// back from its worker goroutine.
+type initResult struct {
+	plug     *NamedPlugin
+	err      error
+	duration time.Duration
+}
+
+// initPlugins calls Init() on all plugins on the list. Plugins are grouped
+// into dependency waves by pluginLevels: plugins within a wave have no
+// dependency on one another and Init in parallel worker goroutines, while
+// each wave only starts once every earlier wave has Init'd successfully.
+// This lets independent subsystems (e.g. etcd, Kafka, HTTP) initialize
+// concurrently instead of paying for their startup cost sequentially.
 func (agent *Agent) initPlugins() error {
 	startTime := time.Now()
-	for i, plug := range agent.plugins {
-		// set currently initialized plugin name
-		agent.currentlyProcessing = string(plug.PluginName + " Init()")
-		err := plug.Init()
-		if err != nil {
-			//Stop the plugins that are initialized
-			for j := i; j >= 0; j-- {
-				err := safeclose.Close(agent.plugins[j])
-				if err != nil {
-					agent.Warn("err closing ", agent.plugins[j].PluginName, " ", err)
+
+	levels, err := agent.pluginLevels()
+	if err != nil {
+		return err
+	}
+
+	var initOrder []*NamedPlugin
+	for _, wave := range levels {
+		agent.currentlyProcessing = fmt.Sprintf("Init() of %d plugin(s)", len(wave))
+
+		results := make(chan initResult, len(wave))
+		var wg sync.WaitGroup
+		for _, plug := range wave {
+			wg.Add(1)
+			go func(plug *NamedPlugin) {
+				defer wg.Done()
+				agent.publish(LifecycleEvent{Type: PluginInitStarted, Plugin: plug.PluginName})
+				plugStart := time.Now()
+				err := plug.Init()
+				results <- initResult{plug, err, time.Since(plugStart)}
+			}(plug)
+		}
+		wg.Wait()
+		close(results)
+
+		var firstErr error
+		for res := range results {
+			if res.err != nil {
+				agent.publish(LifecycleEvent{Type: PluginInitFailed, Plugin: res.plug.PluginName, Err: res.err, Duration: res.duration})
+				if firstErr == nil {
+					firstErr = fmt.Errorf("plugin %s: init error '%s', duration %s", res.plug.PluginName, res.err, res.duration)
 				}
+				continue
 			}
-			initErrTime := time.Since(startTime)
-			return fmt.Errorf(logErrorFmt, plug.PluginName, err, initErrTime.Nanoseconds())
+			agent.publish(LifecycleEvent{Type: PluginInitSucceeded, Plugin: res.plug.PluginName, Duration: res.duration})
+			initOrder = append(initOrder, res.plug)
 		}
 
-		agent.Info(fmt.Sprintf(logSuccessFmt, plug.PluginName))
-		agent.initSuccess = append(agent.initSuccess, plug)
+		if firstErr != nil {
+			// Close only the subtree that actually got initialized, in
+			// reverse of the order it was initialized.
+			for i := len(initOrder) - 1; i >= 0; i-- {
+				agent.publish(LifecycleEvent{Type: PluginCloseStarted, Plugin: initOrder[i].PluginName})
+				if err := safeclose.Close(initOrder[i]); err != nil {
+					agent.publish(LifecycleEvent{Type: PluginCloseFailed, Plugin: initOrder[i].PluginName, Err: err})
+				} else {
+					agent.publish(LifecycleEvent{Type: PluginCloseSucceeded, Plugin: initOrder[i].PluginName})
+				}
+			}
+			return firstErr
+		}
 	}
 	agent.initDuration = time.Since(startTime).Nanoseconds()
 
@@ -184,42 +306,59 @@ func (agent *Agent) initPlugins() error {
 }
 
 // handleAfterInit calls the AfterInit handlers for plugins that can only
-// finish their initialization after  all other plugins have been initialized.
+// finish their initialization after all other plugins have been
+// initialized. AfterInit is scheduled wave by wave, in the same
+// dependency order as initPlugins, so that by the time a plugin's
+// AfterInit runs, every plugin it depends on has already Init'd and
+// AfterInit'd.
 func (agent *Agent) handleAfterInit() error {
 	startTime := time.Now()
-	for _, plug := range agent.plugins {
-		// set currently after-initialized plugin name
-		agent.currentlyProcessing = string(plug.PluginName + " AfterInit()")
-		if plug2, ok := plug.Plugin.(PostInit); ok {
-			agent.Debug("afterInit begin for ", plug.PluginName)
-			err := plug2.AfterInit()
-			if err != nil {
-				agent.Stop()
-				afterInitErrTime := time.Since(startTime)
-				return fmt.Errorf(logPostErrorFmt, plug.PluginName, err, afterInitErrTime.Nanoseconds())
-			}
-			agent.Info(fmt.Sprintf(logPostSuccessFmt, plug.PluginName))
-			agent.afterInitSuccess = append(agent.afterInitSuccess, plug)
-		}
+
+	levels, err := agent.pluginLevels()
+	if err != nil {
+		return err
 	}
-	agent.afterInitDuration = time.Since(startTime).Nanoseconds()
 
-	return nil
-}
+	for _, wave := range levels {
+		agent.currentlyProcessing = fmt.Sprintf("AfterInit() of %d plugin(s)", len(wave))
 
-// Returns list of plugins which are not initialized
-func (agent *Agent) calculateDiff(initialized []*NamedPlugin) []*NamedPlugin {
-	var diff []*NamedPlugin
-	for _, plugin := range agent.plugins {
-		var found bool
-		for _, initialized := range initialized {
-			if plugin == initialized {
-				found = true
+		results := make(chan initResult, len(wave))
+		var wg sync.WaitGroup
+		for _, plug := range wave {
+			plug2, ok := plug.Plugin.(PostInit)
+			if !ok {
+				continue
 			}
+			wg.Add(1)
+			go func(plug *NamedPlugin, postInit PostInit) {
+				defer wg.Done()
+				agent.publish(LifecycleEvent{Type: PluginAfterInitStarted, Plugin: plug.PluginName})
+				plugStart := time.Now()
+				err := postInit.AfterInit()
+				results <- initResult{plug, err, time.Since(plugStart)}
+			}(plug, plug2)
 		}
-		if !found {
-			diff = append(diff, plugin)
+		wg.Wait()
+		close(results)
+
+		var firstErr error
+		for res := range results {
+			if res.err != nil {
+				agent.publish(LifecycleEvent{Type: PluginAfterInitFailed, Plugin: res.plug.PluginName, Err: res.err, Duration: res.duration})
+				if firstErr == nil {
+					firstErr = fmt.Errorf("plugin %s: post-init error '%s', duration %s", res.plug.PluginName, res.err, res.duration)
+				}
+				continue
+			}
+			agent.publish(LifecycleEvent{Type: PluginAfterInitSucceeded, Plugin: res.plug.PluginName, Duration: res.duration})
+		}
+
+		if firstErr != nil {
+			agent.Stop()
+			return firstErr
 		}
 	}
-	return diff
+	agent.afterInitDuration = time.Since(startTime).Nanoseconds()
+
+	return nil
 }
@@ -0,0 +1,164 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+)
+
+// depPlugin is a no-op Plugin that optionally declares dependencies via
+// DependencyAware.
+type depPlugin struct {
+	deps []PluginName
+}
+
+func (p *depPlugin) Init() error  { return nil }
+func (p *depPlugin) Close() error { return nil }
+
+func (p *depPlugin) DependsOn() []PluginName {
+	return p.deps
+}
+
+func named(name string, deps ...string) *NamedPlugin {
+	var depNames []PluginName
+	for _, d := range deps {
+		depNames = append(depNames, PluginName(d))
+	}
+	return &NamedPlugin{PluginName(name), &depPlugin{deps: depNames}}
+}
+
+// legacyPlugin is a no-op Plugin with no DependsOn method at all, so it
+// doesn't implement DependencyAware. It exercises pluginLevels' fallback
+// for plugins that haven't opted into the DAG: an implicit dependency on
+// every plugin declared before them.
+type legacyPlugin struct{}
+
+func (p *legacyPlugin) Init() error  { return nil }
+func (p *legacyPlugin) Close() error { return nil }
+
+func namedLegacy(name string) *NamedPlugin {
+	return &NamedPlugin{PluginName(name), &legacyPlugin{}}
+}
+
+// levelNames flattens pluginLevels' result into one []string per wave, for
+// easy comparison against expected waves regardless of in-wave ordering.
+func levelNames(levels [][]*NamedPlugin) [][]string {
+	var out [][]string
+	for _, wave := range levels {
+		var names []string
+		for _, plug := range wave {
+			names = append(names, string(plug.PluginName))
+		}
+		out = append(out, names)
+	}
+	return out
+}
+
+func sameSet(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[string]bool, len(want))
+	for _, w := range want {
+		seen[w] = true
+	}
+	for _, g := range got {
+		if !seen[g] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPluginLevels(t *testing.T) {
+	cases := []struct {
+		name    string
+		plugins []*NamedPlugin
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name: "disjoint plugins with no declared dependencies run in one wave",
+			plugins: []*NamedPlugin{
+				named("etcd"),
+				named("kafka"),
+				named("http"),
+			},
+			want: [][]string{{"etcd", "kafka", "http"}},
+		},
+		{
+			name: "a dependency chain produces one wave per link",
+			plugins: []*NamedPlugin{
+				named("http"),
+				named("probe", "http"),
+				named("dashboard", "probe"),
+			},
+			want: [][]string{{"http"}, {"probe"}, {"dashboard"}},
+		},
+		{
+			name: "a cycle is rejected",
+			plugins: []*NamedPlugin{
+				named("a", "b"),
+				named("b", "a"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "a plugin with no DependsOn method implicitly depends on everything before it",
+			plugins: []*NamedPlugin{
+				namedLegacy("etcd"),
+				namedLegacy("kafka"),
+				namedLegacy("http"),
+			},
+			want: [][]string{{"etcd"}, {"kafka"}, {"http"}},
+		},
+		{
+			name: "a DependencyAware plugin after legacy ones only waits on its declared deps",
+			plugins: []*NamedPlugin{
+				namedLegacy("etcd"),
+				namedLegacy("kafka"),
+				named("dashboard", "kafka"),
+			},
+			want: [][]string{{"etcd"}, {"kafka"}, {"dashboard"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			agent := &Agent{plugins: c.plugins}
+
+			levels, err := agent.pluginLevels()
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected a cycle detection error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			got := levelNames(levels)
+			if len(got) != len(c.want) {
+				t.Fatalf("got %d wave(s) %v, want %d wave(s) %v", len(got), got, len(c.want), c.want)
+			}
+			for i := range got {
+				if !sameSet(got[i], c.want[i]) {
+					t.Fatalf("wave %d: got %v, want (in any order) %v", i, got[i], c.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+// HealthChecker is optionally implemented by a plugin that wants its
+// readiness tracked independently of the one-shot Init/AfterInit
+// lifecycle, e.g. a plugin that only becomes useful once a Kafka broker
+// connection or an etcd session comes up. The probe plugin aggregates
+// Ready() across every plugin that implements this for its
+// /healthz/ready endpoint.
+type HealthChecker interface {
+	// Ready returns nil when the plugin is able to serve traffic, or an
+	// error describing why it currently cannot.
+	Ready() error
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "time"
+
+// LifecycleEventType enumerates the points in Agent's startup/shutdown
+// sequence that are published to registered LifecycleListeners.
+type LifecycleEventType int
+
+const (
+	// PluginInitStarted is published right before a plugin's Init() call.
+	PluginInitStarted LifecycleEventType = iota
+	// PluginInitSucceeded is published once a plugin's Init() returns nil.
+	PluginInitSucceeded
+	// PluginInitFailed is published once a plugin's Init() returns an error.
+	PluginInitFailed
+	// PluginAfterInitStarted is published right before a plugin's
+	// AfterInit() call.
+	PluginAfterInitStarted
+	// PluginAfterInitSucceeded is published once a plugin's AfterInit()
+	// returns nil.
+	PluginAfterInitSucceeded
+	// PluginAfterInitFailed is published once a plugin's AfterInit()
+	// returns an error.
+	PluginAfterInitFailed
+	// PluginCloseStarted is published right before a plugin's Close() call.
+	PluginCloseStarted
+	// PluginCloseSucceeded is published once a plugin's Close() returns nil.
+	PluginCloseSucceeded
+	// PluginCloseFailed is published once a plugin's Close() returns an
+	// error.
+	PluginCloseFailed
+	// AgentTimedOut is published once Agent.Start gives up waiting for
+	// MaxStartupTime.
+	AgentTimedOut
+)
+
+// String gives the LifecycleEventType a human-readable form, used by the
+// built-in listeners.
+func (t LifecycleEventType) String() string {
+	switch t {
+	case PluginInitStarted:
+		return "PluginInitStarted"
+	case PluginInitSucceeded:
+		return "PluginInitSucceeded"
+	case PluginInitFailed:
+		return "PluginInitFailed"
+	case PluginAfterInitStarted:
+		return "PluginAfterInitStarted"
+	case PluginAfterInitSucceeded:
+		return "PluginAfterInitSucceeded"
+	case PluginAfterInitFailed:
+		return "PluginAfterInitFailed"
+	case PluginCloseStarted:
+		return "PluginCloseStarted"
+	case PluginCloseSucceeded:
+		return "PluginCloseSucceeded"
+	case PluginCloseFailed:
+		return "PluginCloseFailed"
+	case AgentTimedOut:
+		return "AgentTimedOut"
+	default:
+		return "Unknown"
+	}
+}
+
+// LifecycleEvent is published to every registered LifecycleListener as the
+// Agent progresses through Start/Stop. It replaces the ad-hoc
+// logSuccessFmt/logErrorFmt strings, and the full history retained in
+// Agent.events makes calculateDiff unnecessary: a listener can just filter
+// the stream for the event types it cares about.
+type LifecycleEvent struct {
+	Type     LifecycleEventType
+	Plugin   PluginName
+	Err      error
+	Duration time.Duration
+}
+
+// LifecycleListener receives a LifecycleEvent for every lifecycle
+// transition Agent goes through. OnLifecycleEvent must not block; Agent
+// delivers events synchronously, on the goroutine that publishes them.
+// Plugins of one dependency wave Init/AfterInit in parallel goroutines, so
+// OnLifecycleEvent may be called concurrently and must be safe for that.
+type LifecycleListener interface {
+	OnLifecycleEvent(LifecycleEvent)
+}
+
+// RegisterLifecycleListener adds listener to the set notified of every
+// subsequent LifecycleEvent. It must be called before Start.
+func (agent *Agent) RegisterLifecycleListener(listener LifecycleListener) {
+	agent.listeners = append(agent.listeners, listener)
+}
+
+// Events returns the full lifecycle history recorded so far, in the order
+// it was published.
+func (agent *Agent) Events() []LifecycleEvent {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	events := make([]LifecycleEvent, len(agent.events))
+	copy(events, agent.events)
+	return events
+}
+
+// publish records event in the agent's history and delivers it to every
+// registered listener.
+func (agent *Agent) publish(event LifecycleEvent) {
+	agent.mu.Lock()
+	agent.events = append(agent.events, event)
+	agent.mu.Unlock()
+
+	for _, listener := range agent.listeners {
+		listener.OnLifecycleEvent(event)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+// ChannelListener republishes every LifecycleEvent on a buffered channel,
+// so a test can assert on Agent.Start/Stop behavior by draining Events
+// instead of poking at the (now removed) initSuccess/afterInitSuccess
+// slices.
+type ChannelListener struct {
+	// Events receives every LifecycleEvent as it's published. The caller
+	// owns draining it; OnLifecycleEvent drops events once it's full so a
+	// slow/absent reader can't block the agent's startup.
+	Events chan LifecycleEvent
+}
+
+// NewChannelListener creates a ChannelListener with the given channel
+// buffer size.
+func NewChannelListener(buffer int) *ChannelListener {
+	return &ChannelListener{Events: make(chan LifecycleEvent, buffer)}
+}
+
+// OnLifecycleEvent implements LifecycleListener.
+func (l *ChannelListener) OnLifecycleEvent(event LifecycleEvent) {
+	select {
+	case l.Events <- event:
+	default:
+	}
+}
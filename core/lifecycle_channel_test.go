@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "testing"
+
+func TestChannelListenerDeliversEvents(t *testing.T) {
+	listener := NewChannelListener(2)
+
+	listener.OnLifecycleEvent(LifecycleEvent{Type: PluginInitStarted, Plugin: "etcd"})
+	listener.OnLifecycleEvent(LifecycleEvent{Type: PluginInitSucceeded, Plugin: "etcd"})
+
+	first := <-listener.Events
+	if first.Type != PluginInitStarted || first.Plugin != "etcd" {
+		t.Fatalf("got %+v, want PluginInitStarted/etcd", first)
+	}
+	second := <-listener.Events
+	if second.Type != PluginInitSucceeded {
+		t.Fatalf("got %+v, want PluginInitSucceeded", second)
+	}
+}
+
+func TestChannelListenerDropsWhenFull(t *testing.T) {
+	listener := NewChannelListener(1)
+
+	listener.OnLifecycleEvent(LifecycleEvent{Type: PluginInitStarted, Plugin: "etcd"})
+	// The buffer is now full; this second event must be dropped rather
+	// than block OnLifecycleEvent (and so the agent's startup).
+	listener.OnLifecycleEvent(LifecycleEvent{Type: PluginInitSucceeded, Plugin: "etcd"})
+
+	if len(listener.Events) != 1 {
+		t.Fatalf("got %d buffered event(s), want 1", len(listener.Events))
+	}
+	kept := <-listener.Events
+	if kept.Type != PluginInitStarted {
+		t.Fatalf("got %+v, want the first event to have been kept", kept)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "github.com/ligato/cn-infra/logging"
+
+// JSONLogListener turns every LifecycleEvent into a structured log entry
+// on the wrapped Logger, replacing the logSuccessFmt/logErrorFmt strings
+// that used to be formatted ad-hoc inside initPlugins/handleAfterInit.
+type JSONLogListener struct {
+	log logging.Logger
+}
+
+// NewJSONLogListener creates a JSONLogListener that logs through log.
+func NewJSONLogListener(log logging.Logger) *JSONLogListener {
+	return &JSONLogListener{log: log}
+}
+
+// OnLifecycleEvent implements LifecycleListener.
+func (l *JSONLogListener) OnLifecycleEvent(event LifecycleEvent) {
+	fields := logging.Fields{
+		"event":      event.Type.String(),
+		"plugin":     string(event.Plugin),
+		"durationNs": event.Duration.Nanoseconds(),
+	}
+
+	switch event.Type {
+	case PluginInitFailed, PluginAfterInitFailed, PluginCloseFailed, AgentTimedOut:
+		fields["error"] = event.Err.Error()
+		l.log.WithFields(fields).Error(event.Type.String())
+	default:
+		l.log.WithFields(fields).Debug(event.Type.String())
+	}
+}
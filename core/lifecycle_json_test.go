@@ -0,0 +1,82 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ligato/cn-infra/logging"
+)
+
+// recordingLogger is a logging.Logger that records the level it was last
+// logged at and the fields passed via WithFields, so tests can assert on
+// JSONLogListener's behavior without a real logging backend.
+type recordingLogger struct {
+	level  string
+	fields logging.Fields
+}
+
+func (l *recordingLogger) Debug(args ...interface{}) { l.level = "debug" }
+func (l *recordingLogger) Info(args ...interface{})  { l.level = "info" }
+func (l *recordingLogger) Warn(args ...interface{})  { l.level = "warn" }
+func (l *recordingLogger) Error(args ...interface{}) { l.level = "error" }
+
+func (l *recordingLogger) WithField(key string, value interface{}) logging.Logger {
+	return l.WithFields(logging.Fields{key: value})
+}
+
+func (l *recordingLogger) WithFields(fields logging.Fields) logging.Logger {
+	l.fields = fields
+	return l
+}
+
+func TestJSONLogListenerLogsFailuresAsError(t *testing.T) {
+	log := &recordingLogger{}
+	listener := NewJSONLogListener(log)
+
+	listener.OnLifecycleEvent(LifecycleEvent{
+		Type:   PluginInitFailed,
+		Plugin: "kafka",
+		Err:    errors.New("connection refused"),
+	})
+
+	if log.level != "error" {
+		t.Fatalf("got level %q, want %q", log.level, "error")
+	}
+	if log.fields["plugin"] != "kafka" {
+		t.Fatalf("got fields %+v, want plugin=kafka", log.fields)
+	}
+	if log.fields["error"] != "connection refused" {
+		t.Fatalf("got fields %+v, want error=connection refused", log.fields)
+	}
+}
+
+func TestJSONLogListenerLogsSuccessesAsDebug(t *testing.T) {
+	log := &recordingLogger{}
+	listener := NewJSONLogListener(log)
+
+	listener.OnLifecycleEvent(LifecycleEvent{
+		Type:   PluginInitSucceeded,
+		Plugin: "kafka",
+	})
+
+	if log.level != "debug" {
+		t.Fatalf("got level %q, want %q", log.level, "debug")
+	}
+	if log.fields["plugin"] != "kafka" {
+		t.Fatalf("got fields %+v, want plugin=kafka", log.fields)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// PrometheusListener records a per-plugin init/after-init duration
+// histogram, so an operator can graph what's dominating MaxStartupTime
+// instead of grepping log lines for duration numbers.
+type PrometheusListener struct {
+	initDuration      *prometheus.HistogramVec
+	afterInitDuration *prometheus.HistogramVec
+}
+
+// NewPrometheusListener creates a PrometheusListener and registers its
+// collectors with registerer.
+func NewPrometheusListener(registerer prometheus.Registerer) *PrometheusListener {
+	l := &PrometheusListener{
+		initDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "cninfra",
+			Subsystem: "agent",
+			Name:      "plugin_init_duration_seconds",
+			Help:      "Time taken by a plugin's Init() call.",
+		}, []string{"plugin"}),
+		afterInitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "cninfra",
+			Subsystem: "agent",
+			Name:      "plugin_after_init_duration_seconds",
+			Help:      "Time taken by a plugin's AfterInit() call.",
+		}, []string{"plugin"}),
+	}
+	registerer.MustRegister(l.initDuration, l.afterInitDuration)
+	return l
+}
+
+// OnLifecycleEvent implements LifecycleListener.
+func (l *PrometheusListener) OnLifecycleEvent(event LifecycleEvent) {
+	switch event.Type {
+	case PluginInitSucceeded, PluginInitFailed:
+		l.initDuration.WithLabelValues(string(event.Plugin)).Observe(event.Duration.Seconds())
+	case PluginAfterInitSucceeded, PluginAfterInitFailed:
+		l.afterInitDuration.WithLabelValues(string(event.Plugin)).Observe(event.Duration.Seconds())
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheusListenerObservesInitAndAfterInitDurations(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	listener := NewPrometheusListener(registry)
+
+	listener.OnLifecycleEvent(LifecycleEvent{Type: PluginInitSucceeded, Plugin: "kafka", Duration: 10 * time.Millisecond})
+	listener.OnLifecycleEvent(LifecycleEvent{Type: PluginAfterInitFailed, Plugin: "kafka", Duration: 5 * time.Millisecond})
+
+	if got := testutil.CollectAndCount(registry, "cninfra_agent_plugin_init_duration_seconds"); got != 1 {
+		t.Errorf("got %d samples for plugin_init_duration_seconds, want 1", got)
+	}
+	if got := testutil.CollectAndCount(registry, "cninfra_agent_plugin_after_init_duration_seconds"); got != 1 {
+		t.Errorf("got %d samples for plugin_after_init_duration_seconds, want 1", got)
+	}
+}
+
+func TestPrometheusListenerIgnoresOtherEventTypes(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	listener := NewPrometheusListener(registry)
+
+	listener.OnLifecycleEvent(LifecycleEvent{Type: PluginInitStarted, Plugin: "kafka"})
+	listener.OnLifecycleEvent(LifecycleEvent{Type: PluginCloseSucceeded, Plugin: "kafka"})
+
+	if got := testutil.CollectAndCount(registry, "cninfra_agent_plugin_init_duration_seconds"); got != 0 {
+		t.Errorf("got %d samples, want 0: PluginInitStarted/PluginCloseSucceeded must not be observed", got)
+	}
+}
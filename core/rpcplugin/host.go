@@ -0,0 +1,316 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rpcplugin lets core.NewAgent accept plugins that run as separate
+// OS processes, in the style of Hashicorp's go-plugin: the parent agent
+// spawns the child binary, performs a handshake, and forwards
+// Init/AfterInit/Close calls to it over a net/rpc channel. This isolates a
+// faulty plugin (e.g. a native Kafka driver crash) from bringing down the
+// whole agent, and lets operators drop in new plugin binaries without
+// recompiling core.
+package rpcplugin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/rpc"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ligato/cn-infra/core"
+	"github.com/ligato/cn-infra/logging"
+)
+
+// HandshakeTimeout bounds how long PluginHost waits for a freshly spawned
+// child to announce its RPC address on stdout.
+const HandshakeTimeout = 10 * time.Second
+
+// InitialBackoff and MaxBackoff bound the delay the supervisor waits
+// between restarts of a crashed child; the delay doubles after every
+// consecutive crash up to MaxBackoff.
+const (
+	InitialBackoff = 500 * time.Millisecond
+	MaxBackoff     = 30 * time.Second
+)
+
+// remotePluginService is the RPC service name/methods exposed by the child
+// process; it mirrors core.Plugin/core.PostInit/io.Closer over the wire.
+const remotePluginService = "Plugin"
+
+// PluginHost spawns a plugin binary as a separate OS process and wraps it
+// as an ordinary core.NamedPlugin, so it can be passed to core.NewAgent
+// alongside in-process plugins.
+type PluginHost struct {
+	// PluginName is the name under which the remote plugin is registered
+	// with the Agent.
+	PluginName core.PluginName
+	// Cmd is the path to the child binary.
+	Cmd string
+	// Args are passed to the child binary on exec.
+	Args []string
+	// MaxRestarts bounds how many times the supervisor restarts a crashed
+	// child before giving up and surfacing the failure. Zero means
+	// unlimited restarts.
+	MaxRestarts int
+
+	Log logging.Logger
+
+	mu            sync.Mutex
+	client        *rpc.Client
+	proc          *exec.Cmd
+	closing       bool
+	restarts      int
+	backoff       time.Duration
+	afterInitDone bool
+	// exited is closed by supervise once it has called proc.Wait on the
+	// current proc, so Close can wait for that instead of also calling
+	// proc.Wait itself: (*exec.Cmd).Wait may only be called once, and only
+	// from a single goroutine.
+	exited chan struct{}
+
+	// spawnFunc/callFunc are indirections over spawn/call used by
+	// restartAfterCrash, defaulted to the real methods by NewPluginHost.
+	// Tests override them to drive the restart logic without a real child
+	// process.
+	spawnFunc func() error
+	callFunc  func(string) error
+}
+
+// NewPluginHost creates a PluginHost for the given child binary. Call
+// Init/AfterInit/Close on the returned host the same way core.Agent would
+// on any other core.Plugin; wrap it in a core.NamedPlugin to register it.
+func NewPluginHost(name core.PluginName, log logging.Logger, cmd string, args ...string) *PluginHost {
+	host := &PluginHost{
+		PluginName: name,
+		Cmd:        cmd,
+		Args:       args,
+		Log:        log,
+		backoff:    InitialBackoff,
+	}
+	host.spawnFunc = host.spawn
+	host.callFunc = host.call
+	return host
+}
+
+// Init spawns the child process, performs the handshake, starts the crash
+// supervisor, and forwards Init() to the remote plugin.
+func (host *PluginHost) Init() error {
+	if err := host.spawn(); err != nil {
+		return err
+	}
+	go host.supervise()
+	return host.call("Init")
+}
+
+// AfterInit forwards AfterInit() to the remote plugin.
+func (host *PluginHost) AfterInit() error {
+	if err := host.call("AfterInit"); err != nil {
+		return err
+	}
+	host.mu.Lock()
+	host.afterInitDone = true
+	host.mu.Unlock()
+	return nil
+}
+
+// Close stops supervising the child, asks it to Close() over RPC, and
+// terminates the process. It only ever signals the child (via the RPC
+// call and a Kill); it never calls proc.Wait itself, since supervise
+// already owns that call for as long as the child is running.
+func (host *PluginHost) Close() error {
+	host.mu.Lock()
+	host.closing = true
+	client := host.client
+	proc := host.proc
+	exited := host.exited
+	host.mu.Unlock()
+
+	var closeErr error
+	if client != nil {
+		closeErr = client.Call(remotePluginService+".Close", struct{}{}, &struct{}{})
+		client.Close()
+	}
+	if proc != nil && proc.Process != nil {
+		proc.Process.Kill()
+	}
+	if exited != nil {
+		// Block until supervise's Wait() call (the only one allowed on
+		// this proc) has actually reaped the child.
+		<-exited
+	}
+	return closeErr
+}
+
+func (host *PluginHost) call(method string) error {
+	host.mu.Lock()
+	client := host.client
+	host.mu.Unlock()
+
+	if client == nil {
+		return fmt.Errorf("rpcplugin: %s: no RPC connection", host.PluginName)
+	}
+	return client.Call(remotePluginService+"."+method, struct{}{}, &struct{}{})
+}
+
+// spawn starts the child process and dials the RPC address it announces
+// on the first line of its stdout, e.g. "1|tcp|127.0.0.1:54321".
+func (host *PluginHost) spawn() error {
+	proc := exec.Command(host.Cmd, host.Args...)
+	stdout, err := proc.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("rpcplugin: %s: %s", host.PluginName, err)
+	}
+	stderr, err := proc.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("rpcplugin: %s: %s", host.PluginName, err)
+	}
+	if err := proc.Start(); err != nil {
+		return fmt.Errorf("rpcplugin: %s: failed to start %s: %s", host.PluginName, host.Cmd, err)
+	}
+
+	go host.relayOutput(stderr)
+
+	addrCh := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		if scanner.Scan() {
+			addrCh <- scanner.Text()
+		}
+		go host.relayOutput(stdout)
+	}()
+
+	var handshake string
+	select {
+	case handshake = <-addrCh:
+	case <-time.After(HandshakeTimeout):
+		proc.Process.Kill()
+		return fmt.Errorf("rpcplugin: %s: handshake timed out after %s", host.PluginName, HandshakeTimeout)
+	}
+
+	network, addr, err := parseHandshake(handshake)
+	if err != nil {
+		proc.Process.Kill()
+		return fmt.Errorf("rpcplugin: %s: %s", host.PluginName, err)
+	}
+
+	client, err := rpc.Dial(network, addr)
+	if err != nil {
+		proc.Process.Kill()
+		return fmt.Errorf("rpcplugin: %s: dialing %s: %s", host.PluginName, addr, err)
+	}
+
+	host.mu.Lock()
+	host.proc = proc
+	host.client = client
+	host.exited = make(chan struct{})
+	host.mu.Unlock()
+
+	return nil
+}
+
+// parseHandshake parses the go-plugin style handshake line a child writes
+// to stdout once its RPC listener is up:
+//
+//     CORE-PROTOCOL-VERSION|NETWORK|ADDRESS[|PROTOCOL]
+//
+// e.g. "1|tcp|127.0.0.1:54321". It returns the network and address to
+// dial, ignoring the protocol version and the optional trailing protocol
+// field (rpcplugin only speaks net/rpc today).
+func parseHandshake(line string) (network, addr string, err error) {
+	parts := strings.Split(line, "|")
+	if len(parts) < 3 {
+		return "", "", fmt.Errorf("malformed handshake %q", line)
+	}
+	return parts[1], parts[2], nil
+}
+
+// relayOutput copies everything the child writes to its own stdout/stderr
+// into the agent's Logger, so child diagnostics aren't lost.
+func (host *PluginHost) relayOutput(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		host.Log.WithField("pluginName", host.PluginName).Info(scanner.Text())
+	}
+}
+
+// supervise waits for the child process to exit and, unless Close has
+// already been called, restarts it with exponential backoff. It is the
+// only code that ever calls proc.Wait on a given *exec.Cmd: Close signals
+// shutdown via closing/Kill and waits on host.exited instead of also
+// calling Wait, since (*exec.Cmd).Wait must only be called once.
+func (host *PluginHost) supervise() {
+	host.mu.Lock()
+	proc := host.proc
+	exited := host.exited
+	host.mu.Unlock()
+	if proc == nil {
+		return
+	}
+
+	err := proc.Wait()
+	close(exited)
+
+	host.mu.Lock()
+	closing := host.closing
+	host.mu.Unlock()
+	if closing {
+		return
+	}
+
+	host.Log.WithField("pluginName", host.PluginName).Warn("rpcplugin: child exited unexpectedly: ", err)
+	host.restartAfterCrash()
+}
+
+// restartAfterCrash respawns the child, with exponential backoff, and
+// re-issues Init (and AfterInit, if it had already run before the crash)
+// to bring the replacement up to where the dead child left off. It is
+// split out of supervise so tests can drive it directly, through
+// spawnFunc/callFunc, without a real child process.
+func (host *PluginHost) restartAfterCrash() {
+	if host.MaxRestarts > 0 && host.restarts >= host.MaxRestarts {
+		host.Log.WithField("pluginName", host.PluginName).Error("rpcplugin: giving up after ", host.restarts, " restarts")
+		return
+	}
+
+	time.Sleep(host.backoff)
+	host.backoff *= 2
+	if host.backoff > MaxBackoff {
+		host.backoff = MaxBackoff
+	}
+	host.restarts++
+
+	if err := host.spawnFunc(); err != nil {
+		host.Log.WithField("pluginName", host.PluginName).Error("rpcplugin: restart failed: ", err)
+		return
+	}
+	if err := host.callFunc("Init"); err != nil {
+		host.Log.WithField("pluginName", host.PluginName).Error("rpcplugin: re-init after restart failed: ", err)
+		return
+	}
+
+	host.mu.Lock()
+	needsAfterInit := host.afterInitDone
+	host.mu.Unlock()
+	if needsAfterInit {
+		if err := host.callFunc("AfterInit"); err != nil {
+			host.Log.WithField("pluginName", host.PluginName).Error("rpcplugin: re-after-init after restart failed: ", err)
+			return
+		}
+	}
+
+	go host.supervise()
+}
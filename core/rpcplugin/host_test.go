@@ -0,0 +1,171 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcplugin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ligato/cn-infra/logging"
+)
+
+// discardLogger is a no-op logging.Logger: the restart tests only care
+// about spawnFunc/callFunc call order, not what gets logged around them.
+type discardLogger struct{}
+
+func (discardLogger) Debug(args ...interface{}) {}
+func (discardLogger) Info(args ...interface{})  {}
+func (discardLogger) Warn(args ...interface{})  {}
+func (discardLogger) Error(args ...interface{}) {}
+
+func (l discardLogger) WithField(key string, value interface{}) logging.Logger { return l }
+func (l discardLogger) WithFields(fields logging.Fields) logging.Logger        { return l }
+
+func TestParseHandshake(t *testing.T) {
+	cases := []struct {
+		name        string
+		line        string
+		wantNetwork string
+		wantAddr    string
+		wantErr     bool
+	}{
+		{
+			name:        "well formed tcp handshake",
+			line:        "1|tcp|127.0.0.1:54321",
+			wantNetwork: "tcp",
+			wantAddr:    "127.0.0.1:54321",
+		},
+		{
+			name:        "a trailing protocol field is ignored",
+			line:        "1|unix|/tmp/plugin.sock|grpc",
+			wantNetwork: "unix",
+			wantAddr:    "/tmp/plugin.sock",
+		},
+		{
+			name:    "too few fields is rejected",
+			line:    "1|tcp",
+			wantErr: true,
+		},
+		{
+			name:    "empty line is rejected",
+			line:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			network, addr, err := parseHandshake(c.line)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q, got none", c.line)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if network != c.wantNetwork || addr != c.wantAddr {
+				t.Fatalf("got (%q, %q), want (%q, %q)", network, addr, c.wantNetwork, c.wantAddr)
+			}
+		})
+	}
+}
+
+// TestRestartAfterCrashReissuesAfterInit drives restartAfterCrash directly,
+// through spawnFunc/callFunc, to check that a restart re-issues AfterInit
+// whenever it had already completed before the crash, and skips it
+// otherwise, without needing a real child process.
+func TestRestartAfterCrashReissuesAfterInit(t *testing.T) {
+	cases := []struct {
+		name          string
+		afterInitDone bool
+		wantCalls     []string
+	}{
+		{
+			name:          "AfterInit had not run yet: only Init is re-issued",
+			afterInitDone: false,
+			wantCalls:     []string{"Init"},
+		},
+		{
+			name:          "AfterInit had already completed: it is re-issued too",
+			afterInitDone: true,
+			wantCalls:     []string{"Init", "AfterInit"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var calls []string
+			host := &PluginHost{
+				PluginName:    "test",
+				Log:           discardLogger{},
+				backoff:       time.Millisecond,
+				afterInitDone: c.afterInitDone,
+			}
+			host.spawnFunc = func() error { return nil }
+			host.callFunc = func(method string) error {
+				calls = append(calls, method)
+				return nil
+			}
+
+			host.restartAfterCrash()
+
+			if len(calls) != len(c.wantCalls) {
+				t.Fatalf("got calls %v, want %v", calls, c.wantCalls)
+			}
+			for i := range calls {
+				if calls[i] != c.wantCalls[i] {
+					t.Fatalf("got calls %v, want %v", calls, c.wantCalls)
+				}
+			}
+		})
+	}
+}
+
+func TestRestartAfterCrashGivesUpAfterMaxRestarts(t *testing.T) {
+	called := false
+	host := &PluginHost{
+		PluginName:  "test",
+		Log:         discardLogger{},
+		backoff:     time.Millisecond,
+		MaxRestarts: 2,
+		restarts:    2,
+	}
+	host.spawnFunc = func() error { called = true; return nil }
+	host.callFunc = func(string) error { return nil }
+
+	host.restartAfterCrash()
+
+	if called {
+		t.Fatalf("restartAfterCrash must not respawn once MaxRestarts is reached")
+	}
+}
+
+func TestRestartAfterCrashStopsOnSpawnFailure(t *testing.T) {
+	host := &PluginHost{
+		PluginName: "test",
+		Log:        discardLogger{},
+		backoff:    time.Millisecond,
+	}
+	host.spawnFunc = func() error { return errors.New("exec: no such file") }
+	host.callFunc = func(string) error {
+		t.Fatalf("callFunc must not run when spawnFunc fails")
+		return nil
+	}
+
+	host.restartAfterCrash()
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpmux
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ligato/cn-infra/core"
+	"github.com/unrolled/render"
+)
+
+// RegisterPluginAdmin wires up the dynamic plugin enable/disable endpoints
+// on plugin, backed by agent's per-plugin Toggleable state machine:
+//
+//     POST /admin/plugins/{name}/disable
+//     POST /admin/plugins/{name}/enable
+//
+// A request against a plugin that doesn't implement core.Toggleable is
+// rejected with 400.
+func RegisterPluginAdmin(plugin *Plugin, agent *core.Agent) {
+	plugin.RegisterHTTPHandler("/admin/plugins/{name}/disable", pluginToggleHandler(agent, agent.DisablePlugin), "POST")
+	plugin.RegisterHTTPHandler("/admin/plugins/{name}/enable", pluginToggleHandler(agent, agent.EnablePlugin), "POST")
+}
+
+func pluginToggleHandler(agent *core.Agent, action func(core.PluginName) error) func(*render.Render) http.HandlerFunc {
+	return func(formatter *render.Render) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			name := core.PluginName(mux.Vars(req)["name"])
+
+			if err := action(name); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, map[string]string{
+				"name":  string(name),
+				"state": agent.PluginStates()[name].String(),
+			})
+		}
+	}
+}
@@ -15,6 +15,8 @@
 package httpmux
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -22,7 +24,6 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/ligato/cn-infra/core"
 	"github.com/ligato/cn-infra/logging"
-	"github.com/ligato/cn-infra/utils/safeclose"
 	"github.com/unrolled/render"
 )
 
@@ -31,11 +32,52 @@ const (
 	PluginID core.PluginName = "HTTP"
 )
 
+// Config groups the listener, TLS and timeout settings for the HTTP
+// server started by Plugin.AfterInit.
+type Config struct {
+	// Address the server listens on, including the bind interface, e.g.
+	// "0.0.0.0:9191" or "127.0.0.1:9191".
+	Address string
+
+	// CertFile/KeyFile enable TLS via ListenAndServeTLS. Ignored if TLS
+	// is set.
+	CertFile string
+	KeyFile  string
+	// TLS, if set, takes precedence over CertFile/KeyFile and is used
+	// directly as the server's TLSConfig.
+	TLS *tls.Config
+
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	MaxHeaderBytes int
+
+	// ShutdownTimeout bounds how long Close waits for in-flight requests
+	// to complete before the remaining connections are forcibly closed.
+	ShutdownTimeout time.Duration
+}
+
+// DefaultConfig is used when Plugin.Config is left nil: plain HTTP on all
+// interfaces, port 9191, with conservative timeouts and a 5s shutdown
+// drain.
+func DefaultConfig() *Config {
+	return &Config{
+		Address:         "0.0.0.0:9191",
+		ReadTimeout:     10 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		IdleTimeout:     60 * time.Second,
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+func (cfg *Config) usesTLS() bool {
+	return cfg.TLS != nil || (cfg.CertFile != "" && cfg.KeyFile != "")
+}
+
 // Plugin implements the Plugin interface.
 type Plugin struct {
 	LogFactory logging.LogFactory
-	HTTPport   *HTTPPort
-	port 		string
+	Config     *Config
 
 	logging.Logger
 	server    *http.Server
@@ -47,8 +89,11 @@ type Plugin struct {
 // - It prepares Gorilla MUX HTTP Router
 // - registers grpc transport
 func (plugin *Plugin) Init() (err error) {
-	plugin.port = plugin.HTTPport.Port
-	plugin.Logger, err = plugin.LogFactory.NewLogger(string(PluginID) + "-" + plugin.port)
+	if plugin.Config == nil {
+		plugin.Config = DefaultConfig()
+	}
+
+	plugin.Logger, err = plugin.LogFactory.NewLogger(string(PluginID) + "-" + plugin.Config.Address)
 	if err != nil {
 		return err
 	}
@@ -73,17 +118,48 @@ func (plugin *Plugin) RegisterHTTPHandler(path string,
 	return plugin.mx.HandleFunc(path, handler(plugin.formatter)).Methods(methods...)
 }
 
+// RegisterHTTPHandlerWithMiddleware is like RegisterHTTPHandler, but wraps
+// the resulting http.Handler with the given middleware chain before
+// registering it, outermost middleware first (e.g. auth before metrics).
+func (plugin *Plugin) RegisterHTTPHandlerWithMiddleware(path string,
+	handler func(formatter *render.Render) http.HandlerFunc,
+	methods []string,
+	middleware ...func(http.Handler) http.Handler) *mux.Route {
+
+	var h http.Handler = handler(plugin.formatter)
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return plugin.mx.Handle(path, h).Methods(methods...)
+}
+
 // AfterInit starts the HTTP server
 func (plugin *Plugin) AfterInit() error {
-	address := fmt.Sprintf("0.0.0.0:%s", plugin.port)
-	//TODO NICE-to-HAVE make this configurable
-	plugin.server = &http.Server{Addr: address, Handler: plugin.mx}
+	plugin.server = &http.Server{
+		Addr:           plugin.Config.Address,
+		Handler:        plugin.mx,
+		TLSConfig:      plugin.Config.TLS,
+		ReadTimeout:    plugin.Config.ReadTimeout,
+		WriteTimeout:   plugin.Config.WriteTimeout,
+		IdleTimeout:    plugin.Config.IdleTimeout,
+		MaxHeaderBytes: plugin.Config.MaxHeaderBytes,
+	}
 
-	var errCh chan error
+	errCh := make(chan error, 1)
 	go func() {
-		plugin.Info("Listening on http://", address)
-
-		if err := plugin.server.ListenAndServe(); err != nil {
+		var err error
+		if plugin.Config.usesTLS() {
+			certFile, keyFile := plugin.Config.CertFile, plugin.Config.KeyFile
+			if plugin.Config.TLS != nil {
+				certFile, keyFile = "", ""
+			}
+			plugin.Info("Listening on https://", plugin.server.Addr)
+			err = plugin.server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			plugin.Info("Listening on http://", plugin.server.Addr)
+			err = plugin.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		} else {
 			errCh <- nil
@@ -101,13 +177,20 @@ func (plugin *Plugin) AfterInit() error {
 	}
 }
 
-// Close cleans up the resources
+// Close gracefully shuts the HTTP server down, waiting up to
+// Config.ShutdownTimeout for in-flight requests to complete before the
+// remaining connections are forcibly closed.
 func (plugin *Plugin) Close() error {
-	err := safeclose.Close(plugin.server)
-	return err
-}
+	if plugin.server == nil {
+		return nil
+	}
 
-// HTTPPort contains port value as string
-type HTTPPort struct {
-	Port string
-}
\ No newline at end of file
+	ctx, cancel := context.WithTimeout(context.Background(), plugin.Config.ShutdownTimeout)
+	defer cancel()
+
+	if err := plugin.server.Shutdown(ctx); err != nil {
+		plugin.server.Close()
+		return fmt.Errorf("httpmux: shutdown of %s: %s", plugin.Config.Address, err)
+	}
+	return nil
+}
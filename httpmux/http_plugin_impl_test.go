@@ -0,0 +1,97 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpmux
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/unrolled/render"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+
+	if cfg.Address != "0.0.0.0:9191" {
+		t.Errorf("got Address %q, want %q", cfg.Address, "0.0.0.0:9191")
+	}
+	if cfg.usesTLS() {
+		t.Errorf("DefaultConfig must not enable TLS")
+	}
+}
+
+func TestUsesTLS(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+		want bool
+	}{
+		{name: "neither set", cfg: Config{}, want: false},
+		{name: "explicit tls.Config", cfg: Config{TLS: &tls.Config{}}, want: true},
+		{name: "cert and key files", cfg: Config{CertFile: "a.pem", KeyFile: "a.key"}, want: true},
+		{name: "only cert file", cfg: Config{CertFile: "a.pem"}, want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.cfg.usesTLS(); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRegisterHTTPHandlerWithMiddlewareOrdering(t *testing.T) {
+	plugin := &Plugin{
+		mx:        mux.NewRouter(),
+		formatter: render.New(render.Options{}),
+	}
+
+	var order []string
+	middleware := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := func(formatter *render.Render) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "handler")
+		}
+	}
+
+	plugin.RegisterHTTPHandlerWithMiddleware("/test", handler, []string{"GET"},
+		middleware("outer"), middleware("inner"))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	plugin.mx.ServeHTTP(w, req)
+
+	want := []string{"outer", "inner", "handler"}
+	if len(order) != len(want) {
+		t.Fatalf("got call order %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("got call order %v, want %v", order, want)
+		}
+	}
+}
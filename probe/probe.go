@@ -0,0 +1,176 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package probe exposes Kubernetes-style liveness and readiness HTTP
+// endpoints tied to the core.Agent plugin lifecycle, so the agent can be
+// deployed as a first-class Kubernetes workload where the dispatcher only
+// receives traffic once every dependency is provably healthy.
+package probe
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/ligato/cn-infra/core"
+	"github.com/ligato/cn-infra/httpmux"
+	"github.com/unrolled/render"
+)
+
+const (
+	// PluginID used in the Agent Core flavors.
+	PluginID core.PluginName = "PROBE"
+
+	// LivePath is healthy once Init has completed and Close has not yet
+	// been called.
+	LivePath = "/healthz/live"
+	// ReadyPath aggregates the core.HealthChecker.Ready() result of every
+	// plugin that implements it, plus any state published via SetReady.
+	ReadyPath = "/healthz/ready"
+)
+
+// Plugin registers LivePath and ReadyPath on HTTP. It declares an explicit
+// core.DependencyAware dependency on httpmux.PluginID, so core.Agent Inits
+// HTTP (which allocates the mux Plugin.Init registers handlers on) before
+// Plugin itself. httpmux.Plugin does not implement core.DependencyAware, so
+// it implicitly depends on every plugin listed before it: listing Plugin
+// before HTTP in the flavor's plugin list forms a dependency cycle and
+// Agent.Start fails.
+type Plugin struct {
+	HTTP  *httpmux.Plugin
+	Agent *core.Agent
+
+	// StatusCode overrides the default 200 (healthy) / 503 (unhealthy)
+	// pair, e.g. to always reply 200 and let callers inspect the body.
+	StatusCode func(healthy bool) int
+
+	// LivePayload and ReadyPayload override the default
+	// {"live": bool}/{"ready": bool, "failures": {...}} JSON bodies, e.g.
+	// to fold in extra fields a particular deployment's liveness probe
+	// expects. Either may be left nil to keep the default shape.
+	LivePayload  func(live bool) interface{}
+	ReadyPayload func(ready bool, failures map[core.PluginName]string) interface{}
+
+	mu    sync.Mutex
+	live  bool
+	extra map[core.PluginName]error
+}
+
+// DependsOn implements core.DependencyAware: Plugin.Init needs HTTP's mux,
+// which is only allocated once httpmux.Plugin.Init has run.
+func (plugin *Plugin) DependsOn() []core.PluginName {
+	return []core.PluginName{httpmux.PluginID}
+}
+
+// Init registers the liveness and readiness handlers on HTTP.
+func (plugin *Plugin) Init() error {
+	plugin.extra = make(map[core.PluginName]error)
+	plugin.HTTP.RegisterHTTPHandler(LivePath, plugin.liveHandler, "GET")
+	plugin.HTTP.RegisterHTTPHandler(ReadyPath, plugin.readyHandler, "GET")
+	return nil
+}
+
+// AfterInit marks the agent live: every plugin's Init has completed.
+func (plugin *Plugin) AfterInit() error {
+	plugin.mu.Lock()
+	plugin.live = true
+	plugin.mu.Unlock()
+	return nil
+}
+
+// Close marks the agent as no longer live.
+func (plugin *Plugin) Close() error {
+	plugin.mu.Lock()
+	plugin.live = false
+	plugin.mu.Unlock()
+	return nil
+}
+
+// SetReady publishes an out-of-band readiness transition for name (e.g.
+// "Kafka broker connected", "etcd session alive"), flipping ReadyPath
+// without requiring a restart of the plugin it concerns. A nil err marks
+// name healthy.
+func (plugin *Plugin) SetReady(name core.PluginName, err error) {
+	plugin.mu.Lock()
+	defer plugin.mu.Unlock()
+	if err == nil {
+		delete(plugin.extra, name)
+		return
+	}
+	plugin.extra[name] = err
+}
+
+func (plugin *Plugin) liveHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		plugin.mu.Lock()
+		live := plugin.live
+		plugin.mu.Unlock()
+
+		formatter.JSON(w, plugin.statusCode(live), plugin.livePayload(live))
+	}
+}
+
+func (plugin *Plugin) readyHandler(formatter *render.Render) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		failures := plugin.checkReadiness()
+
+		formatter.JSON(w, plugin.statusCode(len(failures) == 0), plugin.readyPayload(len(failures) == 0, failures))
+	}
+}
+
+func (plugin *Plugin) livePayload(live bool) interface{} {
+	if plugin.LivePayload != nil {
+		return plugin.LivePayload(live)
+	}
+	return map[string]bool{"live": live}
+}
+
+func (plugin *Plugin) readyPayload(ready bool, failures map[core.PluginName]string) interface{} {
+	if plugin.ReadyPayload != nil {
+		return plugin.ReadyPayload(ready, failures)
+	}
+	return map[string]interface{}{
+		"ready":    ready,
+		"failures": failures,
+	}
+}
+
+func (plugin *Plugin) checkReadiness() map[core.PluginName]string {
+	failures := make(map[core.PluginName]string)
+
+	for name, agentPlugin := range plugin.Agent.NamedPlugins() {
+		if checker, ok := agentPlugin.(core.HealthChecker); ok {
+			if err := checker.Ready(); err != nil {
+				failures[name] = err.Error()
+			}
+		}
+	}
+
+	plugin.mu.Lock()
+	for name, err := range plugin.extra {
+		failures[name] = err.Error()
+	}
+	plugin.mu.Unlock()
+
+	return failures
+}
+
+func (plugin *Plugin) statusCode(healthy bool) int {
+	if plugin.StatusCode != nil {
+		return plugin.StatusCode(healthy)
+	}
+	if healthy {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
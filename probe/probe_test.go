@@ -0,0 +1,117 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probe
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ligato/cn-infra/core"
+)
+
+// healthPlugin is a no-op Plugin that also implements core.HealthChecker,
+// returning whatever Ready is told to.
+type healthPlugin struct {
+	readyErr error
+}
+
+func (p *healthPlugin) Init() error  { return nil }
+func (p *healthPlugin) Close() error { return nil }
+func (p *healthPlugin) Ready() error { return p.readyErr }
+
+func TestStatusCodeDefaultsToOKAndServiceUnavailable(t *testing.T) {
+	plugin := &Plugin{}
+
+	if got := plugin.statusCode(true); got != http.StatusOK {
+		t.Fatalf("got %d, want %d", got, http.StatusOK)
+	}
+	if got := plugin.statusCode(false); got != http.StatusServiceUnavailable {
+		t.Fatalf("got %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
+
+func TestStatusCodeOverride(t *testing.T) {
+	plugin := &Plugin{StatusCode: func(healthy bool) int { return http.StatusOK }}
+
+	if got := plugin.statusCode(false); got != http.StatusOK {
+		t.Fatalf("got %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestLivePayloadDefaultsToBareBool(t *testing.T) {
+	plugin := &Plugin{}
+
+	got := plugin.livePayload(true)
+	want := map[string]bool{"live": true}
+	if got.(map[string]bool)["live"] != want["live"] {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestLivePayloadOverride(t *testing.T) {
+	plugin := &Plugin{LivePayload: func(live bool) interface{} {
+		return map[string]interface{}{"status": "up", "live": live}
+	}}
+
+	got := plugin.livePayload(false).(map[string]interface{})
+	if got["status"] != "up" || got["live"] != false {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestReadyPayloadOverride(t *testing.T) {
+	plugin := &Plugin{ReadyPayload: func(ready bool, failures map[core.PluginName]string) interface{} {
+		return map[string]interface{}{"ok": ready, "count": len(failures)}
+	}}
+
+	got := plugin.readyPayload(false, map[core.PluginName]string{"kafka": "boom"}).(map[string]interface{})
+	if got["ok"] != false || got["count"] != 1 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestCheckReadinessAggregatesHealthCheckersAndSetReady(t *testing.T) {
+	healthy := &healthPlugin{}
+	unhealthy := &healthPlugin{readyErr: errors.New("no broker")}
+
+	agent := core.NewAgent(nil, 0,
+		&core.NamedPlugin{PluginName: "healthy", Plugin: healthy},
+		&core.NamedPlugin{PluginName: "unhealthy", Plugin: unhealthy},
+	)
+
+	plugin := &Plugin{Agent: agent}
+	plugin.extra = make(map[core.PluginName]error)
+
+	failures := plugin.checkReadiness()
+	if _, ok := failures["unhealthy"]; !ok {
+		t.Fatalf("expected unhealthy plugin to show up in failures, got %v", failures)
+	}
+	if _, ok := failures["healthy"]; ok {
+		t.Fatalf("healthy plugin must not show up in failures, got %v", failures)
+	}
+
+	plugin.SetReady("external", errors.New("etcd session lost"))
+	failures = plugin.checkReadiness()
+	if _, ok := failures["external"]; !ok {
+		t.Fatalf("expected SetReady failure to show up in failures, got %v", failures)
+	}
+
+	plugin.SetReady("external", nil)
+	failures = plugin.checkReadiness()
+	if _, ok := failures["external"]; ok {
+		t.Fatalf("clearing SetReady must remove it from failures, got %v", failures)
+	}
+}